@@ -0,0 +1,125 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cli/cli/internal/ghrepo"
+)
+
+// assetCacheEntry is what we remember about a previously downloaded asset so
+// that a re-run can avoid re-fetching it.
+type assetCacheEntry struct {
+	ETag      string `json:"etag,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Size      int64  `json:"size"`
+}
+
+// downloadCache is a small on-disk, JSON-backed cache of assetCacheEntry
+// keyed by "<owner>/<repo>/<tag>/<asset-id>", used to skip re-downloading
+// assets that haven't changed since the last `gh release download`.
+type downloadCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]assetCacheEntry
+}
+
+func cacheKey(repo ghrepo.Interface, tag string, assetID int64) string {
+	return fmt.Sprintf("%s/%s/%s/%d", repo.RepoOwner(), repo.RepoName(), tag, assetID)
+}
+
+// openDownloadCache loads the cache from the user's cache directory,
+// tolerating a missing or corrupt file by starting fresh.
+func openDownloadCache() *downloadCache {
+	c := &downloadCache{entries: map[string]assetCacheEntry{}}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return c
+	}
+	c.path = filepath.Join(dir, "gh-cli", "release-download-cache.json")
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	_ = json.Unmarshal(data, &c.entries)
+	return c
+}
+
+func (c *downloadCache) get(key string) (assetCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	return e, ok
+}
+
+func (c *downloadCache) set(key string, e assetCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = e
+}
+
+// save persists the cache to disk. Errors are non-fatal: the cache is an
+// optimization, not a source of truth.
+func (c *downloadCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.Marshal(c.entries)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// matchesLocalFile reports whether the file at path still matches the
+// cached entry: the sizes must agree, and when the entry has a digest the
+// file's sha256 digest must agree too. Without a digest, size alone decides;
+// the file's modification time is not compared.
+func matchesLocalFile(path string, entry assetCacheEntry) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if fi.Size() != entry.Size {
+		return false
+	}
+	if entry.Digest == "" {
+		return true
+	}
+	digest, err := digestFile(path, "sha256")
+	if err != nil {
+		return false
+	}
+	return digest == entry.Digest
+}
+
+func digestFile(path, algo string) (string, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
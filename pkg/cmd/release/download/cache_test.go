@@ -0,0 +1,62 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cli/cli/internal/ghrepo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheKey(t *testing.T) {
+	repo := ghrepo.New("owner", "repo")
+	assert.Equal(t, "owner/repo/v1.2.3/42", cacheKey(repo, "v1.2.3", 42))
+}
+
+func TestMatchesLocalFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "asset.tar.gz")
+	require.NoError(t, os.WriteFile(path, []byte("hello world"), 0644))
+
+	digest, err := digestFile(path, "sha256")
+	require.NoError(t, err)
+
+	t.Run("matches on digest", func(t *testing.T) {
+		assert.True(t, matchesLocalFile(path, assetCacheEntry{Size: 11, Digest: digest}))
+	})
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		assert.False(t, matchesLocalFile(path, assetCacheEntry{Size: 11, Digest: "deadbeef"}))
+	})
+
+	t.Run("size mismatch short-circuits before hashing", func(t *testing.T) {
+		assert.False(t, matchesLocalFile(path, assetCacheEntry{Size: 999, Digest: digest}))
+	})
+
+	t.Run("no digest on record falls back to size only", func(t *testing.T) {
+		assert.True(t, matchesLocalFile(path, assetCacheEntry{Size: 11}))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		assert.False(t, matchesLocalFile(filepath.Join(dir, "missing"), assetCacheEntry{Size: 11}))
+	})
+}
+
+func TestDownloadCacheGetSetSave(t *testing.T) {
+	c := &downloadCache{path: filepath.Join(t.TempDir(), "cache.json"), entries: map[string]assetCacheEntry{}}
+
+	_, ok := c.get("owner/repo/v1/1")
+	assert.False(t, ok)
+
+	c.set("owner/repo/v1/1", assetCacheEntry{Digest: "abc", Size: 3})
+	entry, ok := c.get("owner/repo/v1/1")
+	require.True(t, ok)
+	assert.Equal(t, "abc", entry.Digest)
+
+	require.NoError(t, c.save())
+	data, err := os.ReadFile(c.path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "abc")
+}
@@ -0,0 +1,199 @@
+package download
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cli/cli/pkg/cmd/release/shared"
+)
+
+// manifestNames lists the conventional filenames GitHub release workflows
+// use for checksum manifests, checked in order when no --checksum-file is
+// given.
+var manifestNames = []string{"checksums.txt", "CHECKSUMS.txt", "SHA256SUMS", "SHA512SUMS"}
+
+// isChecksumManifest reports whether name looks like a checksum manifest
+// asset, either one of the well-known manifest filenames or a per-file
+// sidecar such as "myapp.tar.gz.sha256".
+func isChecksumManifest(name string) bool {
+	for _, m := range manifestNames {
+		if strings.EqualFold(name, m) {
+			return true
+		}
+	}
+	return strings.HasSuffix(name, ".sha256") || strings.HasSuffix(name, ".sha512")
+}
+
+// parseChecksumManifest parses lines of the form "<hexdigest>  <filename>",
+// as produced by sha256sum/sha512sum, into a filename -> hexdigest map.
+// Lines that don't match the expected shape are ignored.
+func parseChecksumManifest(r io.Reader) (map[string]string, error) {
+	digests := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		digest := strings.ToLower(fields[0])
+		// sha256sum/sha512sum prefix the filename with "*" for binary mode.
+		name := strings.TrimPrefix(fields[1], "*")
+		digests[name] = digest
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error parsing checksum manifest: %w", err)
+	}
+
+	return digests, nil
+}
+
+// loadChecksums resolves the expected digests to verify downloaded assets
+// against, either from an out-of-band --checksum-file or from a manifest
+// (or per-asset sidecar) published alongside the release. It returns an
+// empty map, without error, if no manifest can be found.
+func loadChecksums(httpClient *http.Client, release *shared.Release, opts *DownloadOptions) (map[string]string, error) {
+	if opts.ChecksumFile != "" {
+		r, err := openChecksumSource(httpClient, opts.ChecksumFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading checksum file: %w", err)
+		}
+		defer r.Close()
+		digests, err := parseChecksumManifest(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkDigestAlgo(digests, opts.Algorithm); err != nil {
+			return nil, err
+		}
+		return digests, nil
+	}
+
+	digests := map[string]string{}
+	for _, a := range release.Assets {
+		if !isChecksumManifest(a.Name) {
+			continue
+		}
+
+		r, err := openChecksumSource(httpClient, a.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", a.Name, err)
+		}
+
+		if strings.HasSuffix(a.Name, ".sha256") || strings.HasSuffix(a.Name, ".sha512") {
+			name := strings.TrimSuffix(strings.TrimSuffix(a.Name, ".sha256"), ".sha512")
+			digest, err := checksumForSidecar(r)
+			r.Close()
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s: %w", a.Name, err)
+			}
+			digests[name] = digest
+			continue
+		}
+
+		manifest, err := parseChecksumManifest(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		for name, digest := range manifest {
+			digests[name] = digest
+		}
+	}
+
+	if err := checkDigestAlgo(digests, opts.Algorithm); err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// digestHexLen maps a checksum algorithm to the length of its hex-encoded
+// digest, so a manifest's actual digests can be checked against --algo
+// before download even starts.
+var digestHexLen = map[string]int{
+	"sha256": 64,
+	"sha512": 128,
+}
+
+// algoForHexLen reverse-looks-up digestHexLen, used to name the algorithm a
+// manifest's digests actually match when that doesn't agree with --algo.
+func algoForHexLen(n int) (string, bool) {
+	for algo, l := range digestHexLen {
+		if l == n {
+			return algo, true
+		}
+	}
+	return "", false
+}
+
+// checkDigestAlgo verifies that digests are hex digests of the length
+// implied by algo. Without this, a release that only publishes e.g.
+// SHA512SUMS would silently fail verification under the default --algo
+// sha256 with a confusing "checksum mismatch" rather than telling the user
+// which --algo to pass.
+func checkDigestAlgo(digests map[string]string, algo string) error {
+	want, ok := digestHexLen[algo]
+	if !ok {
+		return nil
+	}
+	for name, digest := range digests {
+		if len(digest) == want {
+			continue
+		}
+		if found, ok := algoForHexLen(len(digest)); ok {
+			return fmt.Errorf("%s: checksum manifest contains %s digests, not %s; retry with --algo %s", name, found, algo, found)
+		}
+		return fmt.Errorf("%s: checksum manifest contains a digest of unexpected length for --algo %s", name, algo)
+	}
+	return nil
+}
+
+// openChecksumSource opens a checksum manifest from either an http(s) URL
+// or a local file path.
+func openChecksumSource(httpClient *http.Client, location string) (io.ReadCloser, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		req, err := http.NewRequest("GET", location, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/octet-stream")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode > 299 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status fetching %s: %s", location, resp.Status)
+		}
+		return resp.Body, nil
+	}
+	return os.Open(location)
+}
+
+// checksumForSidecar derives the expected digest for an asset from its own
+// "<name>.sha256"/"<name>.sha512" sidecar contents, which typically contain
+// nothing but the hex digest.
+func checksumForSidecar(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("empty checksum sidecar")
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum sidecar")
+	}
+	return strings.ToLower(fields[0]), nil
+}
@@ -0,0 +1,75 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsChecksumManifest(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"checksums.txt", true},
+		{"CHECKSUMS.txt", true},
+		{"SHA256SUMS", true},
+		{"SHA512SUMS", true},
+		{"myapp.tar.gz.sha256", true},
+		{"myapp.tar.gz.sha512", true},
+		{"myapp.tar.gz", false},
+		{"README.md", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isChecksumManifest(tt.name))
+		})
+	}
+}
+
+func TestParseChecksumManifest(t *testing.T) {
+	input := strings.NewReader(strings.Join([]string{
+		"# generated by goreleaser",
+		"",
+		"deadbeef  myapp_linux_amd64.tar.gz",
+		"cafef00d *myapp_darwin_amd64.tar.gz",
+		"not a valid line",
+	}, "\n"))
+
+	digests, err := parseChecksumManifest(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"myapp_linux_amd64.tar.gz":  "deadbeef",
+		"myapp_darwin_amd64.tar.gz": "cafef00d",
+	}, digests)
+}
+
+func TestChecksumForSidecar(t *testing.T) {
+	digest, err := checksumForSidecar(strings.NewReader("DEADBEEF  myapp.tar.gz\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "deadbeef", digest)
+
+	_, err = checksumForSidecar(strings.NewReader(""))
+	assert.Error(t, err)
+}
+
+func TestCheckDigestAlgo(t *testing.T) {
+	sha256Digest := strings.Repeat("a", 64)
+	sha512Digest := strings.Repeat("a", 128)
+
+	require.NoError(t, checkDigestAlgo(map[string]string{"a.tar.gz": sha256Digest}, "sha256"))
+
+	err := checkDigestAlgo(map[string]string{"a.tar.gz": sha512Digest}, "sha256")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "contains sha512 digests")
+	assert.Contains(t, err.Error(), "--algo sha512")
+
+	err = checkDigestAlgo(map[string]string{"a.tar.gz": "short"}, "sha256")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected length")
+
+	// An algorithm we don't know the hex length for is left unvalidated.
+	require.NoError(t, checkDigestAlgo(map[string]string{"a.tar.gz": "short"}, "md5"))
+}
@@ -1,11 +1,18 @@
 package download
 
 import (
+	"crypto/sha256"
+	"crypto/sha512"
 	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cli/cli/api"
 	"github.com/cli/cli/internal/ghrepo"
@@ -13,6 +20,7 @@ import (
 	"github.com/cli/cli/pkg/cmdutil"
 	"github.com/cli/cli/pkg/iostreams"
 	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
 )
 
 type DownloadOptions struct {
@@ -24,8 +32,58 @@ type DownloadOptions struct {
 	FilePattern string
 	Destination string
 
-	// maximum number of simultaneous downloads
+	// maximum number of simultaneous downloads; 0 means min(numAssets, 5)
 	Concurrency int
+
+	Verify       bool
+	ChecksumFile string
+	Algorithm    string
+
+	Extract         bool
+	ExtractDir      string
+	StripComponents int
+	KeepArchive     bool
+
+	Retries       int
+	RetryMaxDelay time.Duration
+
+	RateLimitSpec string
+	RateLimit     int64 // bytes/sec, resolved from RateLimitSpec
+
+	// rateLimiter is the single limiter shared by every concurrent transfer
+	// in this run, built once from RateLimit so --rate-limit caps aggregate
+	// throughput rather than being multiplied by --concurrency. Nil means
+	// no limit.
+	rateLimiter *rate.Limiter
+
+	// OutputFile is the -O/--output destination. A value of "-" streams the
+	// single matched asset to IO.Out instead of writing it to disk.
+	OutputFile string
+
+	Clobber      bool
+	SkipExisting bool
+	Mirror       bool
+
+	cache *downloadCache
+
+	// extractedMu guards extractedNames, which tracks the top-level entries
+	// that --extract has written directly into Destination, so mirrorPrune
+	// doesn't delete output it just unpacked. Workers populate this
+	// concurrently, one per asset.
+	extractedMu    sync.Mutex
+	extractedNames map[string]bool
+}
+
+// newHash returns a fresh hash.Hash for the configured algorithm.
+func newHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %q", algo)
+	}
 }
 
 func NewCmdDownload(f *cmdutil.Factory, runF func(*DownloadOptions) error) *cobra.Command {
@@ -33,6 +91,7 @@ func NewCmdDownload(f *cmdutil.Factory, runF func(*DownloadOptions) error) *cobr
 		IO:         f.IOStreams,
 		HttpClient: f.HttpClient,
 	}
+	var noVerify bool
 
 	cmd := &cobra.Command{
 		Use:   "download <tag> [<pattern>]",
@@ -47,7 +106,18 @@ func NewCmdDownload(f *cmdutil.Factory, runF func(*DownloadOptions) error) *cobr
 				opts.FilePattern = args[1]
 			}
 
-			opts.Concurrency = 5
+			if noVerify {
+				opts.Verify = false
+			}
+			if _, err := newHash(opts.Algorithm); opts.Verify && err != nil {
+				return err
+			}
+
+			rateLimit, err := parseRateLimit(opts.RateLimitSpec)
+			if err != nil {
+				return err
+			}
+			opts.RateLimit = rateLimit
 
 			if runF != nil {
 				return runF(opts)
@@ -57,6 +127,22 @@ func NewCmdDownload(f *cmdutil.Factory, runF func(*DownloadOptions) error) *cobr
 	}
 
 	cmd.Flags().StringVarP(&opts.Destination, "destination", "C", ".", "The directory to download files into")
+	cmd.Flags().BoolVar(&opts.Verify, "verify", true, "Verify downloaded assets against a checksum manifest, if one is found")
+	cmd.Flags().BoolVar(&noVerify, "no-verify", false, "Skip checksum verification")
+	cmd.Flags().StringVar(&opts.ChecksumFile, "checksum-file", "", "Path or URL of a checksum manifest to verify against, instead of one published with the release")
+	cmd.Flags().StringVar(&opts.Algorithm, "algo", "sha256", "Checksum algorithm to use when verifying (sha256, sha512)")
+	cmd.Flags().BoolVar(&opts.Extract, "extract", false, "Extract archive assets into the destination after downloading")
+	cmd.Flags().StringVar(&opts.ExtractDir, "extract-dir", "", "Extract each archive into a subdirectory named by this `pattern`, using * as a placeholder for the asset name")
+	cmd.Flags().IntVar(&opts.StripComponents, "strip-components", 0, "Strip `N` leading path components when extracting archives")
+	cmd.Flags().BoolVar(&opts.KeepArchive, "keep-archive", false, "Keep the original archive file after extracting it")
+	cmd.Flags().IntVar(&opts.Retries, "retries", 3, "Number of times to retry a failed download before giving up")
+	cmd.Flags().DurationVar(&opts.RetryMaxDelay, "retry-max-delay", 30*time.Second, "Maximum backoff delay between retries")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", 0, "Number of assets to download at once (default: min(assets, 5))")
+	cmd.Flags().StringVar(&opts.RateLimitSpec, "rate-limit", "", "Limit total download throughput, e.g. \"10MB\"")
+	cmd.Flags().StringVarP(&opts.OutputFile, "output", "O", "", "Write the downloaded asset to `file` instead of the destination directory; use \"-\" to write to stdout")
+	cmd.Flags().BoolVar(&opts.Clobber, "clobber", false, "Overwrite existing files of the same name")
+	cmd.Flags().BoolVar(&opts.SkipExisting, "skip-existing", false, "Skip downloading assets that already exist in the destination")
+	cmd.Flags().BoolVar(&opts.Mirror, "mirror", false, "Like --clobber, and also delete local files no longer present in the release")
 
 	return cmd
 }
@@ -77,6 +163,8 @@ func downloadRun(opts *DownloadOptions) error {
 		return err
 	}
 
+	opts.rateLimiter = newRateLimiter(opts.RateLimit)
+
 	var toDownload []shared.ReleaseAsset
 	for _, a := range release.Assets {
 		if opts.FilePattern != "" {
@@ -84,9 +172,38 @@ func downloadRun(opts *DownloadOptions) error {
 				continue
 			}
 		}
+		if opts.Verify && opts.ChecksumFile == "" && isChecksumManifest(a.Name) {
+			// Manifests are consumed for verification, not downloaded themselves.
+			continue
+		}
 		toDownload = append(toDownload, a)
 	}
 
+	var digests map[string]string
+	if opts.Verify {
+		digests, err = loadChecksums(httpClient, release, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.OutputFile != "" {
+		if len(toDownload) != 1 {
+			return fmt.Errorf("the pattern must match exactly one asset when using --output (matched %d)", len(toDownload))
+		}
+		return downloadToFile(httpClient, toDownload[0], opts, digests[toDownload[0].Name])
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = len(toDownload)
+		if opts.Concurrency > 5 {
+			opts.Concurrency = 5
+		}
+		if opts.Concurrency == 0 {
+			opts.Concurrency = 1
+		}
+	}
+
 	if opts.Destination != "." {
 		err := os.MkdirAll(opts.Destination, 0755)
 		if err != nil {
@@ -94,67 +211,226 @@ func downloadRun(opts *DownloadOptions) error {
 		}
 	}
 
-	opts.IO.StartProgressIndicator()
-	err = downloadAssets(httpClient, toDownload, opts.Destination, opts.Concurrency)
-	opts.IO.StopProgressIndicator()
-	return err
+	if opts.Clobber || opts.Mirror {
+		opts.cache = openDownloadCache()
+	}
+
+	if err := downloadAssets(httpClient, toDownload, opts, digests, baseRepo); err != nil {
+		return err
+	}
+
+	if opts.cache != nil {
+		_ = opts.cache.save()
+	}
+
+	if opts.Mirror {
+		return mirrorPrune(release.Assets, opts)
+	}
+	return nil
+}
+
+// mirrorPrune removes files from opts.Destination that aren't named after one
+// of assets, the release's full asset list, so the directory ends up
+// mirroring the release exactly. Entries that --extract wrote into
+// Destination are left alone: they came from an asset we did download, they
+// just aren't named after it.
+func mirrorPrune(assets []shared.ReleaseAsset, opts *DownloadOptions) error {
+	want := make(map[string]bool, len(assets))
+	for _, a := range assets {
+		want[a.Name] = true
+	}
+	for name := range opts.extractedNames {
+		want[name] = true
+	}
+
+	entries, err := os.ReadDir(opts.Destination)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || want[entry.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(opts.Destination, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downloadToFile handles the -O/--output path: a single matched asset
+// written either to an explicit file path or, for "-", streamed directly to
+// opts.IO.Out for piping into other commands.
+func downloadToFile(httpClient *http.Client, asset shared.ReleaseAsset, opts *DownloadOptions, wantDigest string) error {
+	req, err := http.NewRequest("GET", asset.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 {
+		return api.HandleHTTPError(resp)
+	}
+
+	var out io.Writer
+	if opts.OutputFile == "-" {
+		out = opts.IO.Out
+	} else {
+		f, err := os.OpenFile(opts.OutputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	body := newRateLimitedReader(resp.Body, opts.rateLimiter)
+
+	var h hash.Hash
+	if opts.Verify && wantDigest != "" {
+		h, err = newHash(opts.Algorithm)
+		if err != nil {
+			return err
+		}
+		body = io.TeeReader(body, h)
+	}
+
+	if _, err := io.Copy(out, body); err != nil {
+		return err
+	}
+
+	if h == nil {
+		return nil
+	}
+	if gotDigest := fmt.Sprintf("%x", h.Sum(nil)); gotDigest != wantDigest {
+		return fmt.Errorf("%s: checksum mismatch: expected %s, got %s", asset.Name, wantDigest, gotDigest)
+	}
+	return nil
 }
 
-func downloadAssets(httpClient *http.Client, toDownload []shared.ReleaseAsset, destDir string, numWorkers int) error {
-	if numWorkers == 0 {
+func downloadAssets(httpClient *http.Client, toDownload []shared.ReleaseAsset, opts *DownloadOptions, digests map[string]string, baseRepo ghrepo.Interface) error {
+	if opts.Concurrency <= 0 {
 		return errors.New("the number of concurrent workers needs to be greater than 0")
 	}
 
-	jobs := make(chan shared.ReleaseAsset, len(toDownload))
-	results := make(chan error, len(toDownload))
+	names := make([]string, len(toDownload))
+	for i, a := range toDownload {
+		names[i] = a.Name
+	}
+	renderer := newProgressRenderer(opts.IO, names)
+	renderer.start()
+
+	jobs := make(chan transferJob, len(toDownload))
+	type transferResult struct {
+		job transferJob
+		err error
+	}
+	results := make(chan transferResult, len(toDownload))
 
-	for w := 1; w <= numWorkers; w++ {
+	for w := 1; w <= opts.Concurrency; w++ {
 		go func() {
-			for a := range jobs {
-				results <- downloadAsset(httpClient, a.URL, filepath.Join(destDir, a.Name))
+			for job := range jobs {
+				results <- transferResult{job: job, err: transferAsset(httpClient, job, opts)}
 			}
 		}()
 	}
 
+	isTTY := opts.IO.IsStdoutTTY()
 	for _, a := range toDownload {
-		jobs <- a
+		if !isTTY {
+			fmt.Fprintf(opts.IO.ErrOut, "Downloading %s...\n", a.Name)
+		}
+		job := transferJob{
+			asset:      a,
+			destPath:   filepath.Join(opts.Destination, a.Name),
+			wantDigest: digests[a.Name],
+			progress:   renderer.forName(a.Name),
+		}
+		if opts.cache != nil {
+			job.cacheKey = cacheKey(baseRepo, opts.TagName, a.ID)
+		}
+		jobs <- job
 	}
 	close(jobs)
 
-	var downloadError error
+	cs := opts.IO.ColorScheme()
+	var merr multiError
 	for i := 0; i < len(toDownload); i++ {
-		if err := <-results; err != nil {
-			downloadError = err
+		res := <-results
+		if res.err != nil {
+			merr.Add(res.err)
+		}
+		if !isTTY {
+			if res.err != nil {
+				fmt.Fprintf(opts.IO.ErrOut, "%s %s\n", cs.FailureIcon(), res.err)
+			} else if res.job.wantDigest != "" {
+				fmt.Fprintf(opts.IO.Out, "%s Verified %s\n", cs.SuccessIcon(), res.job.asset.Name)
+			}
 		}
 	}
 
-	return downloadError
+	renderer.stopAndWait()
+
+	return merr.ErrorOrNil()
 }
 
-func downloadAsset(httpClient *http.Client, assetURL, destinationPath string) error {
-	req, err := http.NewRequest("GET", assetURL, nil)
-	if err != nil {
-		return err
+// extractIfRequested unpacks destinationPath, if --extract was given, into its
+// configured extraction directory and removes the archive unless
+// --keep-archive was passed. A file that is not a supported archive makes it
+// return an error.
+func extractIfRequested(asset shared.ReleaseAsset, destinationPath string, opts *DownloadOptions) error {
+	if !opts.Extract {
+		return nil
 	}
 
-	req.Header.Set("Accept", "application/octet-stream")
+	extractDir := opts.Destination
+	usesSubdir := opts.ExtractDir != ""
+	if usesSubdir {
+		extractDir = filepath.Join(opts.Destination, strings.ReplaceAll(opts.ExtractDir, "*", asset.Name))
+	}
+	if err := os.MkdirAll(extractDir, 0755); err != nil {
+		return err
+	}
 
-	resp, err := httpClient.Do(req)
+	names, err := extractArchive(destinationPath, extractDir, opts.StripComponents)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %w", asset.Name, err)
 	}
-	defer resp.Body.Close()
+	opts.recordExtracted(extractDir, usesSubdir, names)
 
-	if resp.StatusCode > 299 {
-		return api.HandleHTTPError(resp)
+	if !opts.KeepArchive {
+		return os.Remove(destinationPath)
 	}
+	return nil
+}
 
-	f, err := os.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
-	if err != nil {
-		return err
+// recordExtracted notes which top-level entries of Destination an extraction
+// produced, for mirrorPrune to exempt from deletion. When extraction used a
+// subdirectory (--extract-dir), the subdirectory itself is the entry that
+// matters; otherwise it's whatever top-level names were written directly
+// into Destination.
+func (opts *DownloadOptions) recordExtracted(extractDir string, usesSubdir bool, names []string) {
+	if !opts.Mirror {
+		return
 	}
-	defer f.Close()
 
-	_, err = io.Copy(f, resp.Body)
-	return err
+	opts.extractedMu.Lock()
+	defer opts.extractedMu.Unlock()
+	if opts.extractedNames == nil {
+		opts.extractedNames = map[string]bool{}
+	}
+
+	if usesSubdir {
+		recordTopLevel(opts.extractedNames, extractDir, opts.Destination)
+		return
+	}
+	for _, name := range names {
+		opts.extractedNames[name] = true
+	}
 }
\ No newline at end of file
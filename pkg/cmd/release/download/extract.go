@@ -0,0 +1,200 @@
+package download
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/h2non/filetype"
+	"github.com/ulikunitz/xz"
+)
+
+// extractArchive detects the archive format of archivePath by its magic
+// bytes and unpacks it into destDir, honoring stripComponents. The original
+// archive is left in place; callers decide whether to remove it. It returns
+// the set of top-level entry names (relative to destDir) that were written,
+// so callers that prune destDir against a want-list can exempt them.
+func extractArchive(archivePath, destDir string, stripComponents int) ([]string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 262)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	kind, err := filetype.Match(head)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind.Extension {
+	case "zip":
+		return extractZip(archivePath, destDir, stripComponents)
+	case "tar":
+		return extractTar(f, destDir, stripComponents)
+	case "gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return extractTar(gz, destDir, stripComponents)
+	case "bz2":
+		return extractTar(bzip2.NewReader(f), destDir, stripComponents)
+	case "xz":
+		xr, err := xz.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		return extractTar(xr, destDir, stripComponents)
+	default:
+		return nil, fmt.Errorf("%s: unrecognized archive format", filepath.Base(archivePath))
+	}
+}
+
+func extractTar(r io.Reader, destDir string, stripComponents int) ([]string, error) {
+	topLevel := map[string]bool{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return topLevelNames(topLevel), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := stripAndResolve(hdr.Name, destDir, stripComponents)
+		if !ok {
+			continue
+		}
+		recordTopLevel(topLevel, name, destDir)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(name, 0755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				return nil, err
+			}
+			out, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode&0777))
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func extractZip(archivePath, destDir string, stripComponents int) ([]string, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	topLevel := map[string]bool{}
+	for _, zf := range zr.File {
+		name, ok := stripAndResolve(zf.Name, destDir, stripComponents)
+		if !ok {
+			continue
+		}
+		recordTopLevel(topLevel, name, destDir)
+
+		if zf.FileInfo().IsDir() {
+			if err := os.MkdirAll(name, 0755); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return nil, err
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		out, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, zf.Mode())
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+		_, err = io.Copy(out, rc)
+		out.Close()
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return topLevelNames(topLevel), nil
+}
+
+// recordTopLevel notes the first path component of target relative to
+// destDir, so the caller can report which entries directly under destDir an
+// extraction touched.
+func recordTopLevel(topLevel map[string]bool, target, destDir string) {
+	rel, err := filepath.Rel(destDir, target)
+	if err != nil || rel == "." {
+		return
+	}
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+	topLevel[parts[0]] = true
+}
+
+func topLevelNames(topLevel map[string]bool) []string {
+	names := make([]string, 0, len(topLevel))
+	for name := range topLevel {
+		names = append(names, name)
+	}
+	return names
+}
+
+// stripAndResolve strips the leading stripComponents path elements from
+// name, joins the remainder onto destDir, and verifies the resolved path
+// does not escape destDir (zip-slip protection). It returns ok=false when
+// the entry should be skipped: stripping consumed the whole path,
+// stripComponents is negative, or the entry is unsafe.
+func stripAndResolve(name string, destDir string, stripComponents int) (string, bool) {
+	name = filepath.ToSlash(name)
+	if strings.HasPrefix(name, "/") {
+		return "", false
+	}
+
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	if stripComponents < 0 || stripComponents >= len(parts) {
+		return "", false
+	}
+	parts = parts[stripComponents:]
+
+	cleanDest := filepath.Clean(destDir)
+	target := filepath.Join(cleanDest, filepath.Join(parts...))
+	if target != cleanDest && !strings.HasPrefix(target, cleanDest+string(os.PathSeparator)) {
+		return "", false
+	}
+	return target, true
+}
@@ -0,0 +1,71 @@
+package download
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripAndResolve(t *testing.T) {
+	destDir := filepath.FromSlash("/dest")
+
+	tests := []struct {
+		name            string
+		entry           string
+		stripComponents int
+		wantOK          bool
+		wantTarget      string
+	}{
+		{
+			name:       "plain file",
+			entry:      "myapp/bin/myapp",
+			wantOK:     true,
+			wantTarget: filepath.Join(destDir, "myapp/bin/myapp"),
+		},
+		{
+			name:            "strip leading component",
+			entry:           "myapp/bin/myapp",
+			stripComponents: 1,
+			wantOK:          true,
+			wantTarget:      filepath.Join(destDir, "bin/myapp"),
+		},
+		{
+			name:            "strip consumes the whole path",
+			entry:           "myapp/bin",
+			stripComponents: 2,
+			wantOK:          false,
+		},
+		{
+			name:   "parent directory traversal",
+			entry:  "../../etc/passwd",
+			wantOK: false,
+		},
+		{
+			name:   "traversal after a safe-looking prefix",
+			entry:  "myapp/../../etc/passwd",
+			wantOK: false,
+		},
+		{
+			name:   "absolute path escaping destDir",
+			entry:  "/etc/passwd",
+			wantOK: false,
+		},
+		{
+			name:            "negative strip-components is rejected, not panicked on",
+			entry:           "myapp/bin/myapp",
+			stripComponents: -1,
+			wantOK:          false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := stripAndResolve(tt.entry, destDir, tt.stripComponents)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.wantTarget, target)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cli/cli/pkg/iostreams"
+)
+
+// formatBytes renders n as a human-readable byte count, e.g. "4.2MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// assetProgress tracks the transfer state of a single asset for rendering.
+type assetProgress struct {
+	name        string
+	total       int64 // 0 when unknown; set once via setTotal, read via loadTotal
+	transferred int64 // updated atomically from the copying goroutine
+	startedAt   time.Time
+
+	mu   sync.Mutex
+	done bool
+	err  error
+}
+
+func (p *assetProgress) addTransferred(n int64) {
+	atomic.AddInt64(&p.transferred, n)
+}
+
+// setTotal records the expected size once it's known (e.g. from a response's
+// Content-Length), from the transferring goroutine.
+func (p *assetProgress) setTotal(n int64) {
+	atomic.StoreInt64(&p.total, n)
+}
+
+func (p *assetProgress) loadTotal() int64 {
+	return atomic.LoadInt64(&p.total)
+}
+
+func (p *assetProgress) finish(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done = true
+	p.err = err
+}
+
+func (p *assetProgress) line() string {
+	transferred := atomic.LoadInt64(&p.transferred)
+
+	p.mu.Lock()
+	done, err := p.done, p.err
+	p.mu.Unlock()
+
+	switch {
+	case done && err != nil:
+		return fmt.Sprintf("✗ %s: %s", p.name, err)
+	case done:
+		return fmt.Sprintf("✓ %s %s", p.name, formatBytes(transferred))
+	}
+
+	elapsed := time.Since(p.startedAt).Seconds()
+	var throughput float64
+	if elapsed > 0 {
+		throughput = float64(transferred) / elapsed
+	}
+
+	if total := p.loadTotal(); total > 0 {
+		pct := float64(transferred) / float64(total) * 100
+		eta := "?"
+		if throughput > 0 {
+			remaining := float64(total-transferred) / throughput
+			if remaining >= 0 {
+				eta = (time.Duration(remaining) * time.Second).String()
+			}
+		}
+		return fmt.Sprintf("%s %s/%s (%.0f%%) %s/s ETA %s",
+			p.name, formatBytes(transferred), formatBytes(total), pct, formatBytes(int64(throughput)), eta)
+	}
+
+	return fmt.Sprintf("%s %s %s/s", p.name, formatBytes(transferred), formatBytes(int64(throughput)))
+}
+
+// progressRenderer draws one line per asset of the run, queued and finished
+// ones included, redrawing in place on a TTY. When stdout is not a TTY it
+// draws nothing; downloadAssets writes plain log lines instead.
+type progressRenderer struct {
+	io     *iostreams.IOStreams
+	assets []*assetProgress
+
+	stop     chan struct{}
+	done     chan struct{}
+	rendered int // number of lines drawn on the previous tick, for a TTY
+}
+
+func newProgressRenderer(io *iostreams.IOStreams, names []string) *progressRenderer {
+	assets := make([]*assetProgress, len(names))
+	for i, name := range names {
+		assets[i] = &assetProgress{name: name, startedAt: time.Now()}
+	}
+	return &progressRenderer{io: io, assets: assets, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+func (r *progressRenderer) forName(name string) *assetProgress {
+	for _, a := range r.assets {
+		if a.name == name {
+			return a
+		}
+	}
+	return &assetProgress{name: name, startedAt: time.Now()}
+}
+
+func (r *progressRenderer) start() {
+	if !r.io.IsStdoutTTY() {
+		return
+	}
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.render()
+			case <-r.stop:
+				r.render()
+				return
+			}
+		}
+	}()
+}
+
+func (r *progressRenderer) render() {
+	out := r.io.Out
+	if r.rendered > 0 {
+		fmt.Fprintf(out, "\x1b[%dA", r.rendered)
+	}
+	for _, a := range r.assets {
+		fmt.Fprintf(out, "\x1b[2K%s\n", a.line())
+	}
+	r.rendered = len(r.assets)
+}
+
+func (r *progressRenderer) stopAndWait() {
+	if !r.io.IsStdoutTTY() {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+// progressReader wraps an io.Reader, reporting bytes read to an
+// assetProgress. It writes no output itself.
+type progressReader struct {
+	r io.Reader
+	p *assetProgress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	if n > 0 {
+		pr.p.addTransferred(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,91 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// parseRateLimit parses a human-readable byte rate such as "10MB" or
+// "512KB" into bytes/sec. An empty string means no limit.
+func parseRateLimit(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	s = strings.TrimSpace(s)
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(s, "GB"):
+		unit = 1 << 30
+		s = strings.TrimSuffix(s, "GB")
+	case strings.HasSuffix(s, "MB"):
+		unit = 1 << 20
+		s = strings.TrimSuffix(s, "MB")
+	case strings.HasSuffix(s, "KB"):
+		unit = 1 << 10
+		s = strings.TrimSuffix(s, "KB")
+	case strings.HasSuffix(s, "B"):
+		s = strings.TrimSuffix(s, "B")
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate limit %q: use a value like 10MB", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// rateLimitBurst is the largest burst a limiter gets, matching io.Copy's
+// default 32KB chunk. newRateLimiter lowers the burst to the rate itself for
+// rates below this. rate.Limiter.WaitN errors outright if asked to wait for
+// more than its burst, so rateLimitedReader caps each read at the burst.
+const rateLimitBurst = 32 * 1024
+
+// newRateLimiter builds the single *rate.Limiter shared across every
+// in-flight transfer for a run, so --rate-limit caps aggregate throughput
+// regardless of --concurrency. A nil limiter means "no limit".
+func newRateLimiter(bytesPerSec int64) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	burst := rateLimitBurst
+	if bytesPerSec < int64(burst) {
+		burst = int(bytesPerSec)
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+}
+
+// rateLimitedReader throttles Read calls against a shared limiter.
+type rateLimitedReader struct {
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+// newRateLimitedReader wraps r so its reads draw from limiter. A nil limiter
+// (no --rate-limit) makes this a no-op passthrough.
+func newRateLimitedReader(r io.Reader, limiter *rate.Limiter) io.Reader {
+	if limiter == nil {
+		return r
+	}
+	return &rateLimitedReader{r: r, limiter: limiter}
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	// Cap the read to the limiter's burst so WaitN is never asked to wait
+	// for more tokens than the limiter can ever hold.
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(context.Background(), n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
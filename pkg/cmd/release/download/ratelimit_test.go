@@ -0,0 +1,74 @@
+package download
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    int64
+		wantErr bool
+	}{
+		{spec: "", want: 0},
+		{spec: "512B", want: 512},
+		{spec: "10KB", want: 10 << 10},
+		{spec: "2.5MB", want: int64(2.5 * (1 << 20))},
+		{spec: "1GB", want: 1 << 30},
+		{spec: "nonsense", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseRateLimit(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	assert.Nil(t, newRateLimiter(0))
+	assert.Nil(t, newRateLimiter(-1))
+
+	// A configured rate below rateLimitBurst gets a burst equal to the rate
+	// itself.
+	limiter := newRateLimiter(100)
+	require.NotNil(t, limiter)
+	assert.Equal(t, 100, limiter.Burst())
+
+	// A generous configured rate is capped at the fixed chunk-sized burst,
+	// not the rate, so it stays independent of --rate-limit.
+	limiter = newRateLimiter(10 << 20)
+	require.NotNil(t, limiter)
+	assert.Equal(t, rateLimitBurst, limiter.Burst())
+}
+
+func TestRateLimitedReaderReadsWithinBurst(t *testing.T) {
+	limiter := newRateLimiter(1 << 20) // 1MB/s, burst = rateLimitBurst (32KB)
+	data := bytes.Repeat([]byte{'x'}, rateLimitBurst*3)
+	r := newRateLimitedReader(bytes.NewReader(data), limiter)
+
+	buf := make([]byte, 64*1024) // larger than burst, like io.Copy's default
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, n, rateLimitBurst)
+
+	got, err := io.ReadAll(io.MultiReader(bytes.NewReader(buf[:n]), r))
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+}
+
+func TestNewRateLimitedReaderNoLimit(t *testing.T) {
+	src := bytes.NewReader([]byte("hello"))
+	r := newRateLimitedReader(src, nil)
+	assert.Same(t, io.Reader(src), r)
+}
@@ -0,0 +1,322 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cli/cli/api"
+	"github.com/cli/cli/pkg/cmd/release/shared"
+	"golang.org/x/time/rate"
+)
+
+// transferJob is a single asset download, tracked through retries and
+// resumption until it either lands at destPath or is given up on.
+type transferJob struct {
+	asset      shared.ReleaseAsset
+	destPath   string
+	wantDigest string
+	progress   *assetProgress // nil when progress rendering is disabled
+	cacheKey   string         // empty when caching is not in play
+}
+
+// multiError collects the errors from a batch of transfers so that one
+// failing asset doesn't hide the failures of the others.
+type multiError struct {
+	errs []error
+}
+
+func (m *multiError) Add(err error) {
+	if err != nil {
+		m.errs = append(m.errs, err)
+	}
+}
+
+func (m *multiError) ErrorOrNil() error {
+	if len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *multiError) Error() string {
+	s := fmt.Sprintf("%d asset(s) failed to download:", len(m.errs))
+	for _, err := range m.errs {
+		s += "\n  " + err.Error()
+	}
+	return s
+}
+
+// transferAsset downloads a single asset with retries and resumable partial
+// transfers, then verifies and extracts it as configured.
+func transferAsset(httpClient *http.Client, job transferJob, opts *DownloadOptions) error {
+	if done, err := handleExistingFile(httpClient, job, opts); done {
+		if job.progress != nil {
+			job.progress.finish(err)
+		}
+		return err
+	}
+
+	partialPath := job.destPath + ".partial"
+
+	var lastErr error
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoffDelay(attempt, lastErr, opts.RetryMaxDelay))
+		}
+
+		lastErr = attemptTransfer(httpClient, job, partialPath, opts.rateLimiter)
+		if lastErr == nil {
+			break
+		}
+		if !isTransientError(lastErr) {
+			break
+		}
+	}
+
+	if job.progress != nil {
+		job.progress.finish(lastErr)
+	}
+
+	if lastErr != nil {
+		os.Remove(partialPath)
+		return fmt.Errorf("%s: %w", job.asset.Name, lastErr)
+	}
+
+	if err := os.Rename(partialPath, job.destPath); err != nil {
+		return fmt.Errorf("%s: %w", job.asset.Name, err)
+	}
+
+	if err := verifyDigest(job, opts); err != nil {
+		os.Remove(job.destPath)
+		return err
+	}
+
+	if opts.cache != nil && job.cacheKey != "" {
+		updateCacheEntry(job, opts)
+	}
+
+	return extractIfRequested(job.asset, job.destPath, opts)
+}
+
+// handleExistingFile decides what to do when job.destPath already exists,
+// before any network request is made. It returns done=true when
+// transferAsset should stop here, with err nil for a successful skip or
+// non-nil for a hard failure.
+func handleExistingFile(httpClient *http.Client, job transferJob, opts *DownloadOptions) (done bool, err error) {
+	if _, statErr := os.Stat(job.destPath); statErr != nil {
+		return false, nil
+	}
+
+	if opts.SkipExisting {
+		return true, nil
+	}
+	if !opts.Clobber && !opts.Mirror {
+		return true, fmt.Errorf("%s already exists (use --clobber to overwrite)", job.destPath)
+	}
+
+	if opts.cache == nil || job.cacheKey == "" {
+		return false, nil
+	}
+
+	entry, ok := opts.cache.get(job.cacheKey)
+	if !ok {
+		return false, nil
+	}
+	if matchesLocalFile(job.destPath, entry) {
+		return true, nil
+	}
+
+	notModified, err := checkNotModified(httpClient, job.asset.URL, entry)
+	if err != nil {
+		return true, err
+	}
+	return notModified, nil
+}
+
+// checkNotModified issues a conditional GET using the cached ETag/
+// updated-at and reports whether the server confirmed the asset is
+// unchanged with a 304.
+func checkNotModified(httpClient *http.Client, assetURL string, entry assetCacheEntry) (bool, error) {
+	req, err := http.NewRequest("GET", assetURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.UpdatedAt != "" {
+		req.Header.Set("If-Modified-Since", entry.UpdatedAt)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusNotModified, nil
+}
+
+// updateCacheEntry records the freshly downloaded asset's digest and size so
+// a future run can recognize it as unchanged.
+func updateCacheEntry(job transferJob, opts *DownloadOptions) {
+	digest, err := digestFile(job.destPath, "sha256")
+	if err != nil {
+		return
+	}
+	fi, err := os.Stat(job.destPath)
+	if err != nil {
+		return
+	}
+	opts.cache.set(job.cacheKey, assetCacheEntry{
+		UpdatedAt: job.asset.UpdatedAt.UTC().Format(http.TimeFormat),
+		Digest:    digest,
+		Size:      fi.Size(),
+	})
+}
+
+// attemptTransfer makes a single HTTP request for job, resuming from
+// partialPath if it already has bytes on disk.
+func attemptTransfer(httpClient *http.Client, job transferJob, partialPath string, limiter *rate.Limiter) error {
+	var resumeFrom int64
+	if fi, err := os.Stat(partialPath); err == nil {
+		resumeFrom = fi.Size()
+	}
+
+	req, err := http.NewRequest("GET", job.asset.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	if resumeFrom > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeFrom))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return &transientError{err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return newRetryableStatusError(resp)
+	}
+	if resp.StatusCode > 299 && resp.StatusCode != http.StatusPartialContent {
+		return api.HandleHTTPError(resp)
+	}
+
+	// The server may not support Range requests and instead send the whole
+	// body back with 200; in that case restart the file from scratch.
+	flags := os.O_WRONLY | os.O_CREATE
+	if resp.StatusCode == http.StatusPartialContent && resumeFrom > 0 {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+		resumeFrom = 0
+	}
+
+	f, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	body := newRateLimitedReader(resp.Body, limiter)
+	if job.progress != nil {
+		if resp.ContentLength > 0 {
+			job.progress.setTotal(resumeFrom + resp.ContentLength)
+		}
+		body = &progressReader{r: body, p: job.progress}
+	}
+
+	if _, err := io.Copy(f, body); err != nil {
+		return &transientError{err: err}
+	}
+	return nil
+}
+
+// verifyDigest hashes the completed file at job.destPath and compares it
+// against job.wantDigest. This re-reads the whole file from disk rather than
+// hashing incrementally while attemptTransfer writes it: a transfer can span
+// several retries, each resuming partialPath from wherever the last attempt
+// left off via Range, so a hash.Hash scoped to one attemptTransfer call
+// never sees the bytes earlier attempts wrote. Hashing once the file is
+// assembled is the simplest way to get a digest over all of it; it costs a
+// second full read per verified asset.
+func verifyDigest(job transferJob, opts *DownloadOptions) error {
+	if !opts.Verify || job.wantDigest == "" {
+		return nil
+	}
+
+	h, err := newHash(opts.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(job.destPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	gotDigest := fmt.Sprintf("%x", h.Sum(nil))
+	if gotDigest != job.wantDigest {
+		return fmt.Errorf("%s: checksum mismatch: expected %s, got %s", job.asset.Name, job.wantDigest, gotDigest)
+	}
+	return nil
+}
+
+// transientError marks an error as a network or server-side failure worth
+// retrying, as opposed to e.g. a permission error or a checksum mismatch.
+type transientError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+func isTransientError(err error) bool {
+	_, ok := err.(*transientError)
+	return ok
+}
+
+// newRetryableStatusError builds a transientError for a 429/5xx response,
+// honoring a numeric Retry-After header when present.
+func newRetryableStatusError(resp *http.Response) *transientError {
+	te := &transientError{err: errors.New("server responded with " + resp.Status)}
+	if ra := resp.Header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil {
+			te.retryAfter = time.Duration(secs) * time.Second
+		}
+	}
+	return te
+}
+
+// backoffDelay returns the delay to wait before the given retry attempt
+// (1-indexed): the server's Retry-After hint if lastErr carried one,
+// otherwise exponential backoff with full jitter, capped at max.
+func backoffDelay(attempt int, lastErr error, max time.Duration) time.Duration {
+	if te, ok := lastErr.(*transientError); ok && te.retryAfter > 0 {
+		return te.retryAfter
+	}
+
+	base := max
+	if shift := attempt - 1; shift < 32 {
+		if d := time.Duration(1<<uint(shift)) * time.Second; d < max {
+			base = d
+		}
+	}
+	return time.Duration(rand.Int63n(int64(base) + 1))
+}
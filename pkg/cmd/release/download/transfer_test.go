@@ -0,0 +1,41 @@
+package download
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	max := 30 * time.Second
+
+	t.Run("honors a Retry-After hint", func(t *testing.T) {
+		lastErr := &transientError{err: errors.New("rate limited"), retryAfter: 5 * time.Second}
+		assert.Equal(t, 5*time.Second, backoffDelay(1, lastErr, max))
+	})
+
+	t.Run("jittered exponential backoff is capped at max", func(t *testing.T) {
+		for attempt := 1; attempt <= 10; attempt++ {
+			delay := backoffDelay(attempt, errors.New("boom"), max)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.LessOrEqual(t, delay, max)
+		}
+	})
+
+	t.Run("grows the ceiling with attempt number", func(t *testing.T) {
+		// attempt 1 can wait at most ~1s; by attempt 6 the base already
+		// exceeds max, so the ceiling should be pinned there.
+		for i := 0; i < 50; i++ {
+			assert.LessOrEqual(t, backoffDelay(1, errors.New("boom"), max), time.Second)
+		}
+		assert.LessOrEqual(t, backoffDelay(6, errors.New("boom"), max), max)
+	})
+}
+
+func TestIsTransientError(t *testing.T) {
+	assert.True(t, isTransientError(&transientError{err: errors.New("boom")}))
+	assert.False(t, isTransientError(errors.New("boom")))
+	assert.False(t, isTransientError(nil))
+}